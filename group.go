@@ -0,0 +1,115 @@
+package httpctx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ServerSpec pairs a server with the listen function used to run it, as
+// produced by [ListenAndServe], [ListenAndServeTLS], [Serve] or [ServeTLS].
+// Use one of the constructors below to build a ServerSpec instead of
+// populating it directly.
+type ServerSpec struct {
+	srv      *http.Server
+	opts     []Option
+	runFunc  func(ctx context.Context, srv *http.Server, opts ...Option) error
+	certFile string
+	keyFile  string
+	ln       net.Listener
+}
+
+// WithServer builds a [ServerSpec] that runs srv via [ListenAndServe].
+func WithServer(srv *http.Server, opts ...Option) ServerSpec {
+	return ServerSpec{srv: srv, opts: opts, runFunc: ListenAndServe}
+}
+
+// WithServerTLS builds a [ServerSpec] that runs srv via [ListenAndServeTLS].
+func WithServerTLS(srv *http.Server, certFile, keyFile string, opts ...Option) ServerSpec {
+	return ServerSpec{
+		srv: srv, opts: opts, certFile: certFile, keyFile: keyFile,
+		runFunc: func(ctx context.Context, srv *http.Server, opts ...Option) error {
+			return ListenAndServeTLS(ctx, srv, certFile, keyFile, opts...)
+		},
+	}
+}
+
+// WithListener builds a [ServerSpec] that runs srv via [Serve] using ln.
+func WithListener(srv *http.Server, ln net.Listener, opts ...Option) ServerSpec {
+	return ServerSpec{
+		srv: srv, opts: opts, ln: ln,
+		runFunc: func(ctx context.Context, srv *http.Server, opts ...Option) error {
+			return Serve(ctx, srv, ln, opts...)
+		},
+	}
+}
+
+// WithListenerTLS builds a [ServerSpec] that runs srv via [ServeTLS] using ln.
+func WithListenerTLS(srv *http.Server, ln net.Listener, certFile, keyFile string, opts ...Option) ServerSpec {
+	return ServerSpec{
+		srv: srv, opts: opts, ln: ln, certFile: certFile, keyFile: keyFile,
+		runFunc: func(ctx context.Context, srv *http.Server, opts ...Option) error {
+			return ServeTLS(ctx, srv, ln, certFile, keyFile, opts...)
+		},
+	}
+}
+
+// Group supervises a fixed set of [http.Server]s sharing a single context.
+// The first error returned by any server cancels the shared context, which in
+// turn triggers the graceful shutdown of the remaining servers, same as if
+// the parent ctx had been cancelled directly.
+type Group struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	errc   chan error
+}
+
+// RunAll starts every spec concurrently under ctx and returns a [Group]
+// tracking them. Call [Group.Wait] to block until all servers have shut down.
+func RunAll(ctx context.Context, specs ...ServerSpec) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+
+	g := &Group{
+		cancel: cancel,
+		errc:   make(chan error, len(specs)),
+	}
+
+	g.wg.Add(len(specs))
+	for _, spec := range specs {
+		spec := spec
+		go func() {
+			defer g.wg.Done()
+			if err := spec.runFunc(ctx, spec.srv, spec.opts...); err != nil {
+				g.errc <- err
+				cancel()
+			}
+		}()
+	}
+
+	return g
+}
+
+// Stop cancels the shared context, triggering the graceful shutdown of every
+// server in the group, same as cancelling the ctx passed to [RunAll].
+func (g *Group) Stop() {
+	g.cancel()
+}
+
+// Wait blocks until every server supervised by g has shut down and returns a
+// joined error built from every non-nil error any of them returned, or nil if
+// they all shut down cleanly.
+func (g *Group) Wait() error {
+	go func() {
+		g.wg.Wait()
+		close(g.errc)
+	}()
+
+	var errs []error
+	for err := range g.errc {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,119 @@
+package httpctx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGroupShutsDownAllOnCtxCancel(t *testing.T) {
+	t.Parallel()
+
+	srv1 := &http.Server{Addr: ":0"}
+	srv2 := &http.Server{Addr: ":0"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g := RunAll(ctx, WithServer(srv1), WithServer(srv2))
+
+	errc := make(chan error)
+	go func() { errc <- g.Wait() }()
+
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown timeout")
+	}
+}
+
+func TestGroupCancelsOnFirstError(t *testing.T) {
+	t.Parallel()
+
+	// eat up a random port so srv1 fails to listen
+	taken, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	srv1 := &http.Server{Addr: taken.Addr().String()}
+	srv2 := &http.Server{Addr: ":0"}
+
+	g := RunAll(context.Background(), WithServer(srv1), WithServer(srv2))
+
+	errc := make(chan error)
+	go func() { errc <- g.Wait() }()
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("expected an error from the taken port")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("group did not shut down after the first error")
+	}
+}
+
+func TestGroupSharedDrainBroadcastDoesNotPanic(t *testing.T) {
+	t.Parallel()
+
+	srv1 := &http.Server{Addr: ":0"}
+	srv2 := &http.Server{Addr: ":0"}
+
+	drainOpt, drain := WithDrainBroadcast()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g := RunAll(ctx, WithServer(srv1, drainOpt), WithServer(srv2, drainOpt))
+
+	errc := make(chan error)
+	go func() { errc <- g.Wait() }()
+
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown timeout")
+	}
+
+	select {
+	case <-drain:
+	default:
+		t.Fatal("drain channel was never closed")
+	}
+}
+
+func TestGroupStop(t *testing.T) {
+	t.Parallel()
+
+	srv := &http.Server{Addr: ":0"}
+
+	g := RunAll(context.Background(), WithServer(srv))
+
+	errc := make(chan error)
+	go func() { errc <- g.Wait() }()
+
+	g.Stop()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown timeout")
+	}
+}
@@ -1,20 +1,37 @@
 // httpctx provides a context aware implementation of ListenAndServe, ListenAndServeTLS, Serve and ServeTLS, useful for integration with golang.org/x/sync/errgroup.
+// It also provides Run and Group for wiring up signal-driven shutdown and supervising multiple servers.
 package httpctx
 
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 )
 
 type config struct {
-	beforeShutdown  func(timeout time.Duration)
-	afterShutdown   func(error)
-	shutdownTimeout time.Duration
+	beforeShutdown      func(timeout time.Duration)
+	afterShutdown       func(error)
+	shutdownTimeout     time.Duration
+	forceCloseOnTimeout bool
+	signals             []os.Signal
+	restartSignal       os.Signal
+	maxConnections      int
+	tcpKeepAlive        time.Duration
+	onShutdownInitiated func()
+	closeDrain          func()
 }
 
+// defaultSignals is the signal set used by Run, RunTLS, RunServe and RunServeTLS
+// unless overridden via WithSignals.
+var defaultSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
 type Option func(*config)
 
 // WithShutdownTimeout controls the timeout for the shutdown of the http server.
@@ -25,6 +42,97 @@ func WithShutdownTimeout(d time.Duration) Option {
 	}
 }
 
+// WithForceCloseOnTimeout controls whether [http.Server.Close] is called when
+// [http.Server.Shutdown] returns [context.DeadlineExceeded]. This forcibly drops
+// any connections still in flight (e.g. hijacked or streaming ones) instead of
+// leaving them to run until the process exits. Default is true, it has no
+// effect if the shutdown timeout is disabled via WithShutdownTimeout(0).
+func WithForceCloseOnTimeout(b bool) Option {
+	return func(o *config) {
+		o.forceCloseOnTimeout = b
+	}
+}
+
+// WithSignals overrides the set of signals that [Run], [RunTLS], [RunServe] and
+// [RunServeTLS] listen for. Default is [os.Interrupt] and [syscall.SIGTERM].
+// It has no effect on [ListenAndServe], [ListenAndServeTLS], [Serve] and [ServeTLS].
+func WithSignals(sig ...os.Signal) Option {
+	return func(o *config) {
+		o.signals = sig
+	}
+}
+
+// WithGracefulRestart enables zero-downtime restarts: when sig is received,
+// the current binary is re-exec'd (via [os.Executable]) inheriting the
+// listeners obtained through [InheritedListener], and once the child signals
+// readiness via [SignalReady] the parent drains in-flight requests through the
+// normal shutdown path and exits. If the child doesn't signal readiness within
+// a bounded timeout, the parent assumes it's broken and keeps serving.
+// Unsupported on windows, where it falls back to a forceful restart: the
+// current process just shuts down, relying on something else to start the
+// replacement.
+func WithGracefulRestart(sig os.Signal) Option {
+	return func(o *config) {
+		o.restartSignal = sig
+	}
+}
+
+// WithMaxConnections caps the number of simultaneously accepted connections to
+// n. Once the cap is reached, Accept blocks until a connection is closed.
+// Default is 0, which means no limit. Applies to [ListenAndServe],
+// [ListenAndServeTLS], [Serve] and [ServeTLS].
+func WithMaxConnections(n int) Option {
+	return func(o *config) {
+		o.maxConnections = n
+	}
+}
+
+// WithTCPKeepAlive enables TCP keep-alive on accepted connections with period
+// d. Default is 0, which leaves the connection's keep-alive setting untouched.
+// Applies to [ListenAndServe], [ListenAndServeTLS], [Serve] and [ServeTLS].
+func WithTCPKeepAlive(d time.Duration) Option {
+	return func(o *config) {
+		o.tcpKeepAlive = d
+	}
+}
+
+// OnShutdownInitiated registers fn to be called the moment ctx is cancelled
+// (or a graceful restart hands off, see WithGracefulRestart), before
+// [http.Server.Shutdown] is called.
+func OnShutdownInitiated(fn func()) Option {
+	return func(o *config) {
+		o.onShutdownInitiated = fn
+	}
+}
+
+// WithDrainBroadcast returns an Option alongside a channel that's closed at
+// the same moment OnShutdownInitiated fires, i.e. before [http.Server.Shutdown]
+// is called. Long-lived handlers (websocket, SSE, long-poll) can select on the
+// returned channel to proactively close their side instead of being cut off
+// once the shutdown deadline elapses:
+//
+//	drainOpt, drain := httpctx.WithDrainBroadcast()
+//	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		select {
+//		case <-drain:
+//			return
+//		case <-r.Context().Done():
+//			return
+//		}
+//	})
+//	httpctx.ListenAndServe(ctx, srv, drainOpt)
+//
+// The returned Option may be passed to more than one server (e.g. across the
+// [ServerSpec]s of a [Group]); the channel is still only closed once.
+func WithDrainBroadcast() (Option, <-chan struct{}) {
+	drain := make(chan struct{})
+	var once sync.Once
+	closeDrain := func() { once.Do(func() { close(drain) }) }
+	return func(o *config) {
+		o.closeDrain = closeDrain
+	}, drain
+}
+
 // BeforeShutdown registers fn to be called before [http.Server.Shutdown].
 func BeforeShutdown(fn func(timeout time.Duration)) Option {
 	return func(o *config) {
@@ -42,47 +150,86 @@ func AfterShutdown(fn func(err error)) Option {
 
 // ListenAndServe is like [http.Server.ListenAndServe] but also takes in a context.
 // When the context is cancelled ListenAndServe waits until the server is shutdown, forwarding the error.
+// If [WithGracefulRestart] is set, the listener is built via [InheritedListener]
+// instead of [net.Listen] so it participates in the restart; it's unregistered
+// again once this call returns.
 func ListenAndServe(ctx context.Context, srv *http.Server, opts ...Option) error {
 	cfg := newConfig(opts...)
-	return start(ctx, cfg, srv, srv.ListenAndServe)
+
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := listen(addr, cfg)
+	if err != nil {
+		return err
+	}
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+
+	return start(ctx, cfg, srv, func() error { return srv.Serve(wrapped) })
 }
 
 // ListenAndServeTLS is like [http.Server.ListenAndServeTLS] but also takes in a context.
 // When the context is cancelled ListenAndServeTLS waits until the server is shutdown, forwarding the error.
+// If [WithGracefulRestart] is set, the listener is built via [InheritedListener]
+// instead of [net.Listen] so it participates in the restart; it's unregistered
+// again once this call returns.
 func ListenAndServeTLS(ctx context.Context, srv *http.Server, certFile, keyFile string, opts ...Option) error {
 	cfg := newConfig(opts...)
-	return start(ctx, cfg, srv, func() error { return srv.ListenAndServeTLS(certFile, keyFile) })
+
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	ln, err := listen(addr, cfg)
+	if err != nil {
+		return err
+	}
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+
+	return start(ctx, cfg, srv, func() error { return srv.ServeTLS(wrapped, certFile, keyFile) })
+}
+
+// listen builds the listener for addr. It only goes through
+// [InheritedListener] (and thus registers with the graceful-restart fd table)
+// when cfg has [WithGracefulRestart] set; otherwise it's a plain
+// [net.Listen] that never touches the restart registry.
+func listen(addr string, cfg config) (net.Listener, error) {
+	if cfg.restartSignal != nil {
+		return InheritedListener(addr)
+	}
+	return net.Listen("tcp", addr)
 }
 
 // Serve is like [http.Server.Serve] but also takes in a context.
 // When the context is cancelled Serve waits until the server is shutdown, forwarding the error.
+// If ln was obtained from [InheritedListener], it's unregistered again once
+// this call returns.
 func Serve(ctx context.Context, srv *http.Server, ln net.Listener, opts ...Option) error {
 	cfg := newConfig(opts...)
-	return start(ctx, cfg, srv, func() error { return srv.Serve(ln) })
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+	return start(ctx, cfg, srv, func() error { return srv.Serve(wrapped) })
 }
 
 // ServeTLS is like [http.Server.ServeTLS] but also takes in a context.
 // When the context is cancelled ServeTLS waits until the server is shutdown, forwarding the error.
+// If ln was obtained from [InheritedListener], it's unregistered again once
+// this call returns.
 func ServeTLS(ctx context.Context, srv *http.Server, ln net.Listener, certFile, keyFile string, opts ...Option) error {
 	cfg := newConfig(opts...)
-	return start(ctx, cfg, srv, func() error { return srv.ServeTLS(ln, certFile, keyFile) })
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+	return start(ctx, cfg, srv, func() error { return srv.ServeTLS(wrapped, certFile, keyFile) })
 }
 
 func start(ctx context.Context, cfg config, srv *http.Server, runFunc func() error) error {
 	errc := make(chan error)
 
 	go func() {
-		<-ctx.Done()
-
-		timeout := context.Background()
-		if cfg.shutdownTimeout > 0 {
-			var cancel context.CancelFunc
-			timeout, cancel = context.WithTimeout(context.Background(), cfg.shutdownTimeout)
-			defer cancel()
-		}
-
-		cfg.beforeShutdown(cfg.shutdownTimeout)
-		errc <- srv.Shutdown(timeout)
+		errc <- awaitShutdown(ctx, cfg, srv)
 	}()
 
 	if err := runFunc(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -94,11 +241,65 @@ func start(ctx context.Context, cfg config, srv *http.Server, runFunc func() err
 	return err
 }
 
+// awaitShutdown blocks until ctx is cancelled, or until a graceful restart
+// (see WithGracefulRestart) succeeds, then shuts srv down.
+func awaitShutdown(ctx context.Context, cfg config, srv *http.Server) error {
+	var restartc chan os.Signal
+	if cfg.restartSignal != nil {
+		restartc = make(chan os.Signal, 1)
+		signal.Notify(restartc, cfg.restartSignal)
+		defer signal.Stop(restartc)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+		case <-restartc:
+			if !restartGraceful() {
+				// the child never signalled readiness, keep serving and
+				// wait for either ctx to be cancelled or another attempt.
+				continue
+			}
+		}
+		break
+	}
+
+	return shutdown(cfg, srv)
+}
+
+func shutdown(cfg config, srv *http.Server) error {
+	timeout := context.Background()
+	if cfg.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		timeout, cancel = context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+		defer cancel()
+	}
+
+	cfg.onShutdownInitiated()
+	if cfg.closeDrain != nil {
+		cfg.closeDrain()
+	}
+
+	cfg.beforeShutdown(cfg.shutdownTimeout)
+	err := srv.Shutdown(timeout)
+	if errors.Is(err, context.DeadlineExceeded) && cfg.forceCloseOnTimeout {
+		if closeErr := srv.Close(); closeErr != nil {
+			err = fmt.Errorf("shutdown timed out, force close failed: %w: %w", err, closeErr)
+		} else {
+			err = fmt.Errorf("shutdown timed out, connections force closed: %w", err)
+		}
+	}
+	return err
+}
+
 func newConfig(opts ...Option) config {
 	cfg := config{
-		beforeShutdown:  func(time.Duration) {},
-		afterShutdown:   func(error) {},
-		shutdownTimeout: 30 * time.Second,
+		beforeShutdown:      func(time.Duration) {},
+		afterShutdown:       func(error) {},
+		onShutdownInitiated: func() {},
+		shutdownTimeout:     30 * time.Second,
+		forceCloseOnTimeout: true,
+		signals:             defaultSignals,
 	}
 
 	for _, opt := range opts {
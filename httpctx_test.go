@@ -124,6 +124,106 @@ func TestShutdownTimeout(t *testing.T) {
 	}
 }
 
+func TestForceCloseOnTimeout(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	s := http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+
+			// unblock the client
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+
+			// but block the handler
+			time.Sleep(30 * time.Second)
+		}),
+	}
+	errc := make(chan error)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// start the server, has 1s to shutdown, force close defaults to on
+	go func() {
+		errc <- Serve(ctx, &s, ln, WithShutdownTimeout(time.Second))
+	}()
+
+	// wait for server to start (unfortunate) and kick off the handler
+	time.Sleep(time.Second)
+	if _, err := http.Get("http://" + addr); err != nil {
+		t.Fatal(err)
+	}
+
+	// kill it, shutdown should timeout and the force close should kick in
+	cancel()
+	err = <-errc
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if want := "shutdown timed out, connections force closed: " + context.DeadlineExceeded.Error(); err.Error() != want {
+		t.Fatalf("got error %q, want %q", err, want)
+	}
+
+	// the listener should be unusable now that the server has shut down
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Fatal("expected connections to the closed listener to fail")
+	}
+}
+
+func TestNoForceCloseOnTimeout(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	unblock := make(chan struct{})
+	s := http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+
+			// unblock the client
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+
+			// block the handler until the test is done with it
+			<-unblock
+		}),
+	}
+	defer close(unblock)
+
+	errc := make(chan error)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		errc <- Serve(ctx, &s, ln, WithShutdownTimeout(time.Second), WithForceCloseOnTimeout(false))
+	}()
+
+	// wait for server to start (unfortunate) and kick off the handler
+	time.Sleep(time.Second)
+	if _, err := http.Get("http://" + addr); err != nil {
+		t.Fatal(err)
+	}
+
+	// kill it, shutdown should timeout and the stuck connection should not be
+	// force closed, so the error is the bare deadline error, not wrapped
+	cancel()
+	if err := <-errc; err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+}
+
 func TestShutdownNoTimeout(t *testing.T) {
 	t.Parallel()
 
@@ -168,6 +268,47 @@ func TestShutdownNoTimeout(t *testing.T) {
 	}
 }
 
+func TestOnShutdownInitiatedAndDrainBroadcast(t *testing.T) {
+	t.Parallel()
+
+	srv := http.Server{
+		Addr: ":0",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initiated := make(chan struct{})
+	drainOpt, drain := WithDrainBroadcast()
+
+	errc := make(chan error)
+	go func() {
+		errc <- ListenAndServe(
+			ctx,
+			&srv,
+			OnShutdownInitiated(func() { close(initiated) }),
+			drainOpt,
+		)
+	}()
+	cancel()
+
+	select {
+	case <-initiated:
+	case <-time.After(time.Second):
+		t.Fatal("OnShutdownInitiated not called")
+	}
+
+	select {
+	case <-drain:
+	case <-time.After(time.Second):
+		t.Fatal("drain channel not closed")
+	}
+
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestHooks(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,76 @@
+package httpctx
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// wrapListener applies the listener-level options (WithMaxConnections,
+// WithTCPKeepAlive) configured in cfg to ln.
+func wrapListener(ln net.Listener, cfg config) net.Listener {
+	if cfg.tcpKeepAlive > 0 {
+		ln = &tcpKeepAliveListener{Listener: ln, period: cfg.tcpKeepAlive}
+	}
+	if cfg.maxConnections > 0 {
+		ln = newLimitListener(ln, cfg.maxConnections)
+	}
+	return ln
+}
+
+// tcpKeepAliveListener wraps a net.Listener, enabling TCP keep-alive with a
+// fixed period on every accepted *net.TCPConn.
+type tcpKeepAliveListener struct {
+	net.Listener
+	period time.Duration
+}
+
+func (ln *tcpKeepAliveListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := c.(*net.TCPConn); ok {
+		tc.SetKeepAlive(true)
+		tc.SetKeepAlivePeriod(ln.period)
+	}
+	return c, nil
+}
+
+// limitListener wraps a net.Listener, capping the number of simultaneously
+// accepted connections. Equivalent to golang.org/x/net/netutil.LimitListener.
+type limitListener struct {
+	net.Listener
+	sem chan struct{}
+}
+
+func newLimitListener(ln net.Listener, n int) *limitListener {
+	return &limitListener{Listener: ln, sem: make(chan struct{}, n)}
+}
+
+func (ln *limitListener) Accept() (net.Conn, error) {
+	ln.sem <- struct{}{}
+
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		<-ln.sem
+		return nil, err
+	}
+
+	return &limitListenerConn{Conn: c, release: func() { <-ln.sem }}, nil
+}
+
+// limitListenerConn releases its slot in the semaphore the first time it's
+// closed, whether that's done explicitly or by the server after the request
+// completes.
+type limitListenerConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
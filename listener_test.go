@@ -0,0 +1,101 @@
+package httpctx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaxConnections(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	block := make(chan struct{})
+	release := make(chan struct{})
+	s := http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			block <- struct{}{}
+			<-release
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errc := make(chan error)
+	go func() {
+		errc <- Serve(ctx, &s, ln, WithMaxConnections(1))
+	}()
+	defer func() {
+		close(release)
+		cancel()
+		<-errc
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	go http.Get("http://" + addr)
+	<-block // first request is in flight, holding the only slot
+
+	second := make(chan error, 1)
+	go func() {
+		c, err := net.DialTimeout("tcp", addr, 500*time.Millisecond)
+		if err == nil {
+			// send a request, so the handler runs if the connection is accepted
+			_, err = c.Write([]byte("GET / HTTP/1.1\r\nHost: " + addr + "\r\n\r\n"))
+		}
+		second <- err
+	}()
+
+	select {
+	case <-block:
+		t.Fatal("a second connection was accepted past the limit")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	<-second
+}
+
+func TestTCPKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	s := http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errc := make(chan error)
+	go func() {
+		errc <- Serve(ctx, &s, ln, WithTCPKeepAlive(30*time.Second))
+	}()
+	defer func() {
+		cancel()
+		<-errc
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
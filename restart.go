@@ -0,0 +1,156 @@
+package httpctx
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// env vars used to hand a listening socket and the parent's pid down to a
+// graceful-restart child, see WithGracefulRestart.
+const (
+	envListenFDs = "HTTPCTX_LISTEN_FDS"
+	envPPID      = "HTTPCTX_PPID"
+)
+
+// filer is implemented by listeners that can hand out a dup'd *os.File of
+// their underlying fd, e.g. *net.TCPListener.
+type filer interface {
+	File() (*os.File, error)
+}
+
+// listenerEntry is a registered listener along with the index it was
+// inherited at (or would be re-exported at on the next restart).
+type listenerEntry struct {
+	idx int
+	ln  filer
+}
+
+// listeners tracks the file-backed listeners currently in use by servers that
+// opted into WithGracefulRestart, so a restart can pass their fds, in the same
+// order they were inherited, down to the child. A fresh *os.File is dup'd from
+// each of these on every restart attempt, since each dup is consumed (closed)
+// once handed to the child. Entries are added by InheritedListener and removed
+// once their server shuts down, so a listener that's no longer in use never
+// blocks, or misorders, a later restart.
+var (
+	listenerMu    sync.Mutex
+	listeners     []listenerEntry
+	nextListenIdx int
+)
+
+// InheritedListener returns the next listener inherited from a
+// graceful-restart parent (see WithGracefulRestart), or falls back to
+// net.Listen("tcp", addr) if this process wasn't started as a restart child,
+// or no more listeners were inherited. Call it once per listener, in the same
+// order every time the binary starts, so fds line up across restarts, and
+// pass the result to Serve/ServeTLS along with WithGracefulRestart so it's
+// unregistered again once that server shuts down.
+func InheritedListener(addr string) (net.Listener, error) {
+	listenerMu.Lock()
+	idx := nextListenIdx
+	nextListenIdx++
+	listenerMu.Unlock()
+
+	ln, err := fileListener(idx)
+	if err != nil {
+		return nil, err
+	}
+	if ln == nil {
+		ln, err = net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	registerListener(idx, ln)
+	return ln, nil
+}
+
+// fileListener reconstructs the idx-th inherited listener from its fd, or
+// returns a nil listener if fewer than idx+1 listeners were inherited.
+func fileListener(idx int) (net.Listener, error) {
+	n, _ := strconv.Atoi(os.Getenv(envListenFDs))
+	if idx >= n {
+		return nil, nil
+	}
+
+	f := os.NewFile(uintptr(3+idx), "httpctx-listener-"+strconv.Itoa(idx))
+	ln, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("httpctx: reconstruct inherited listener %d: %w", idx, err)
+	}
+
+	return ln, nil
+}
+
+// registerListener remembers ln under idx, if it can hand out a backing
+// *os.File, so it can later be dup'd and handed down to a restart child, in
+// the relative order its InheritedListener calls were made.
+func registerListener(idx int, ln net.Listener) {
+	f, ok := ln.(filer)
+	if !ok {
+		return
+	}
+
+	listenerMu.Lock()
+	listeners = append(listeners, listenerEntry{idx: idx, ln: f})
+	listenerMu.Unlock()
+}
+
+// unregisterListener removes ln from the restart registry, if it's in there.
+// Call it once a listener registered via InheritedListener is done being
+// served, so a closed listener doesn't linger in the fd table forever and
+// doesn't block or misorder a later restart.
+func unregisterListener(ln net.Listener) {
+	f, ok := ln.(filer)
+	if !ok {
+		return
+	}
+
+	listenerMu.Lock()
+	defer listenerMu.Unlock()
+	for i, e := range listeners {
+		if e.ln == f {
+			listeners = append(listeners[:i:i], listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// activeListeners returns the listeners currently registered, ordered by the
+// sequence their InheritedListener calls were made in.
+func activeListeners() []filer {
+	listenerMu.Lock()
+	defer listenerMu.Unlock()
+
+	sorted := append([]listenerEntry(nil), listeners...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].idx < sorted[j].idx })
+
+	fs := make([]filer, len(sorted))
+	for i, e := range sorted {
+		fs[i] = e.ln
+	}
+	return fs
+}
+
+// SignalReady tells a graceful-restart parent (see WithGracefulRestart) that
+// this process has finished starting up and is ready to take over traffic.
+// It is a no-op if the process wasn't started as a restart child.
+func SignalReady() error {
+	ppid := os.Getenv(envPPID)
+	if ppid == "" {
+		return nil
+	}
+
+	pid, err := strconv.Atoi(ppid)
+	if err != nil {
+		return fmt.Errorf("httpctx: parse %s: %w", envPPID, err)
+	}
+
+	return signalReady(pid)
+}
@@ -0,0 +1,159 @@
+package httpctx
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInheritedListenerFallsBackToListen(t *testing.T) {
+	t.Parallel()
+
+	ln, err := InheritedListener(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if ln.Addr() == nil {
+		t.Fatal("expected a bound listener")
+	}
+}
+
+// TestGracefulRestartEndToEnd drives the real restartGraceful/spawnChild path:
+// it registers a listener, re-execs the test binary scoped down to
+// TestGracefulRestartChildHelper via os.Args, and checks that the child
+// reconstructs the listener from its inherited fd, signals readiness, and
+// actually serves the handoff request.
+//
+// It mutates the process-global os.Args, so unlike the rest of this
+// package's tests it can't run with t.Parallel().
+func TestGracefulRestartEndToEnd(t *testing.T) {
+	if os.Getenv(envListenFDs) != "" {
+		t.Skip("running as a restart child, see TestGracefulRestartChildHelper")
+	}
+
+	ln, err := InheritedListener(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterListener(ln)
+	defer ln.Close()
+	addr := ln.Addr().String()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origArgs := os.Args
+	os.Args = []string{exe, "-test.run=^TestGracefulRestartChildHelper$"}
+	restarted := restartGraceful()
+	os.Args = origArgs
+
+	if !restarted {
+		t.Fatal("expected the child to signal readiness")
+	}
+
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(body); got != "restarted" {
+		t.Fatalf("got response %q, want %q", got, "restarted")
+	}
+}
+
+// TestGracefulRestartChildHelper is not a test in its own right: it only does
+// anything when run as the restart child spawned by
+// TestGracefulRestartEndToEnd, identified by envListenFDs being set. Run any
+// other way, it's a no-op, so a plain `go test ./...` doesn't try to bind or
+// serve anything here.
+func TestGracefulRestartChildHelper(t *testing.T) {
+	if os.Getenv(envListenFDs) == "" {
+		t.Skip("only runs as a restart child spawned by TestGracefulRestartEndToEnd")
+	}
+
+	ln, err := InheritedListener(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "restarted")
+		}),
+	}
+	go srv.Serve(ln)
+
+	if err := SignalReady(); err != nil {
+		t.Fatal(err)
+	}
+
+	// keep the process alive long enough for the parent to probe us
+	time.Sleep(2 * time.Second)
+}
+
+// TestGracefulRestartTimeoutKeepsServing exercises the other side of
+// restartGraceful: a child that never calls SignalReady must not be allowed
+// to wedge the parent forever, and the parent must still be serving once it
+// gives up.
+//
+// It mutates the process-global os.Args and restartReadyTimeout, so unlike
+// the rest of this package's tests it can't run with t.Parallel().
+func TestGracefulRestartTimeoutKeepsServing(t *testing.T) {
+	if os.Getenv(envListenFDs) != "" {
+		t.Skip("running as a restart child, see TestGracefulRestartChildNeverReady")
+	}
+
+	ln, err := InheritedListener(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterListener(ln)
+	defer ln.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origArgs := os.Args
+	origTimeout := restartReadyTimeout
+	restartReadyTimeout = 200 * time.Millisecond
+	os.Args = []string{exe, "-test.run=^TestGracefulRestartChildNeverReady$"}
+
+	restarted := restartGraceful()
+
+	os.Args = origArgs
+	restartReadyTimeout = origTimeout
+
+	if restarted {
+		t.Fatal("expected the restart to time out, the child never signals readiness")
+	}
+
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err != nil {
+		t.Fatalf("parent's listener should still be accepting connections: %v", err)
+	}
+}
+
+// TestGracefulRestartChildNeverReady is the counterpart to
+// TestGracefulRestartChildHelper for the timeout path: as a restart child it
+// deliberately never calls SignalReady, so the parent's wait times out.
+func TestGracefulRestartChildNeverReady(t *testing.T) {
+	if os.Getenv(envListenFDs) == "" {
+		t.Skip("only runs as a restart child spawned by TestGracefulRestartTimeoutKeepsServing")
+	}
+
+	time.Sleep(10 * time.Second)
+}
@@ -0,0 +1,100 @@
+//go:build !windows
+
+package httpctx
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// restartReadyTimeout bounds how long the parent waits for a freshly exec'd
+// child to call SignalReady before giving up on the restart and resuming
+// normal service. Variable, rather than a constant, so tests can shrink it.
+var restartReadyTimeout = 10 * time.Second
+
+// restartGraceful spawns a copy of the running binary inheriting the current
+// listener fds and waits, up to restartReadyTimeout, for it to call
+// SignalReady. It reports whether the child took over successfully.
+func restartGraceful() bool {
+	// install the readiness handler before spawning the child: it's a fresh
+	// exec and can call SignalReady before we'd otherwise start listening,
+	// in which case SIGUSR1 would hit us with its default (terminating)
+	// disposition instead of being caught here.
+	readyc := make(chan os.Signal, 1)
+	signal.Notify(readyc, syscall.SIGUSR1)
+	defer signal.Stop(readyc)
+
+	proc, err := spawnChild()
+	if err != nil {
+		return false
+	}
+
+	select {
+	case <-readyc:
+		return true
+	case <-time.After(restartReadyTimeout):
+		// broken child, don't leave it behind wedging the parent, and reap
+		// it so it doesn't linger as a zombie.
+		proc.Kill()
+		proc.Wait()
+		return false
+	}
+}
+
+// spawnChild fork/execs the running binary, handing down every listener
+// currently registered via InheritedListener, in the order they were
+// requested, starting at fd 3.
+func spawnChild() (*os.Process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("httpctx: resolve executable for restart: %w", err)
+	}
+
+	lns := activeListeners()
+
+	lnFiles := make([]*os.File, 0, len(lns))
+	for _, ln := range lns {
+		f, err := ln.File()
+		if err != nil {
+			for _, opened := range lnFiles {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("httpctx: dup listener fd for restart: %w", err)
+		}
+		lnFiles = append(lnFiles, f)
+	}
+
+	files := make([]*os.File, 0, 3+len(lnFiles))
+	files = append(files, os.Stdin, os.Stdout, os.Stderr)
+	files = append(files, lnFiles...)
+
+	env := append(os.Environ(),
+		fmt.Sprintf("%s=%d", envListenFDs, len(lnFiles)),
+		fmt.Sprintf("%s=%d", envPPID, os.Getpid()),
+	)
+
+	proc, err := os.StartProcess(exe, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: files,
+	})
+
+	// os.StartProcess (like fork/exec) dups each fd into the child; our copies,
+	// obtained from net.TCPListener.File(), are no longer needed once it
+	// returns, whether it succeeded or not.
+	for _, f := range lnFiles {
+		f.Close()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("httpctx: spawn restart child: %w", err)
+	}
+
+	return proc, nil
+}
+
+func signalReady(pid int) error {
+	return syscall.Kill(pid, syscall.SIGUSR1)
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package httpctx
+
+import "errors"
+
+// restartGraceful has no portable way to hand listening sockets down to a
+// child process on windows, so graceful restart falls back to a forceful one:
+// the current process proceeds straight to its normal shutdown, relying on
+// something else (a service manager, a supervisor) to start the replacement.
+func restartGraceful() bool {
+	return true
+}
+
+func signalReady(pid int) error {
+	return errors.New("httpctx: SignalReady is not supported on windows")
+}
@@ -0,0 +1,72 @@
+package httpctx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os/signal"
+)
+
+// Run is like [ListenAndServe] but also shuts down on the first incoming signal
+// from the set configured via [WithSignals] (default [os.Interrupt] and
+// [syscall.SIGTERM]), wrapping ctx with [signal.NotifyContext] internally. This
+// saves callers from having to plumb their own signal handling and cancellation
+// when wiring an http server into something like [golang.org/x/sync/errgroup].
+func Run(ctx context.Context, srv *http.Server, opts ...Option) error {
+	cfg := newConfig(opts...)
+	ctx, stop := signal.NotifyContext(ctx, cfg.signals...)
+	defer stop()
+
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := listen(addr, cfg)
+	if err != nil {
+		return err
+	}
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+
+	return start(ctx, cfg, srv, func() error { return srv.Serve(wrapped) })
+}
+
+// RunTLS is like [Run] but for [ListenAndServeTLS].
+func RunTLS(ctx context.Context, srv *http.Server, certFile, keyFile string, opts ...Option) error {
+	cfg := newConfig(opts...)
+	ctx, stop := signal.NotifyContext(ctx, cfg.signals...)
+	defer stop()
+
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	ln, err := listen(addr, cfg)
+	if err != nil {
+		return err
+	}
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+
+	return start(ctx, cfg, srv, func() error { return srv.ServeTLS(wrapped, certFile, keyFile) })
+}
+
+// RunServe is like [Run] but for [Serve].
+func RunServe(ctx context.Context, srv *http.Server, ln net.Listener, opts ...Option) error {
+	cfg := newConfig(opts...)
+	ctx, stop := signal.NotifyContext(ctx, cfg.signals...)
+	defer stop()
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+	return start(ctx, cfg, srv, func() error { return srv.Serve(wrapped) })
+}
+
+// RunServeTLS is like [Run] but for [ServeTLS].
+func RunServeTLS(ctx context.Context, srv *http.Server, ln net.Listener, certFile, keyFile string, opts ...Option) error {
+	cfg := newConfig(opts...)
+	ctx, stop := signal.NotifyContext(ctx, cfg.signals...)
+	defer stop()
+	defer unregisterListener(ln)
+	wrapped := wrapListener(ln, cfg)
+	return start(ctx, cfg, srv, func() error { return srv.ServeTLS(wrapped, certFile, keyFile) })
+}
@@ -0,0 +1,67 @@
+package httpctx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunUnblocksOnCtxCancel(t *testing.T) {
+	t.Parallel()
+
+	srv := &http.Server{
+		Addr: ":0",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	errc := make(chan error)
+	go func() {
+		errc <- Run(ctx, srv)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown timeout")
+	}
+}
+
+func TestRunUnblocksOnSignal(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{}
+
+	errc := make(chan error)
+	go func() {
+		errc <- RunServe(context.Background(), srv, ln, WithSignals(syscall.SIGUSR1))
+	}()
+
+	// give RunServe a moment to install the signal handler
+	time.Sleep(100 * time.Millisecond)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown timeout")
+	}
+}